@@ -0,0 +1,196 @@
+package sftp
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// CompressionAlgo identifies a codec the compression driver can transparently
+// apply to objects it stores in S3.
+type CompressionAlgo string
+
+const (
+	CompressionNone CompressionAlgo = ""
+	CompressionGzip CompressionAlgo = "gzip"
+	CompressionZstd CompressionAlgo = "zstd"
+)
+
+func (a CompressionAlgo) suffix() string {
+	switch a {
+	case CompressionGzip:
+		return ".gz"
+	case CompressionZstd:
+		return ".zst"
+	default:
+		return ""
+	}
+}
+
+func (a CompressionAlgo) contentEncoding() string {
+	switch a {
+	case CompressionGzip:
+		return "gzip"
+	case CompressionZstd:
+		return "zstd"
+	default:
+		return ""
+	}
+}
+
+// algoFromSuffix returns the codec implied by a file's suffix, and the name
+// with that suffix stripped off.
+func algoFromSuffix(name string) (CompressionAlgo, string) {
+	if stripped := strings.TrimSuffix(name, CompressionGzip.suffix()); stripped != name {
+		return CompressionGzip, stripped
+	}
+	if stripped := strings.TrimSuffix(name, CompressionZstd.suffix()); stripped != name {
+		return CompressionZstd, stripped
+	}
+	return CompressionNone, name
+}
+
+// CompressedDriver wraps an S3Driver so that PutFile transparently encodes
+// files with the configured codec and GetFile decodes them again, letting
+// bandwidth-constrained SFTP clients benefit from server-side compression
+// without ever negotiating it themselves. Stat and ListDir report the
+// uncompressed logical name, stripping the suffix the wrapper adds on write.
+type CompressedDriver struct {
+	*S3Driver
+	algo CompressionAlgo
+}
+
+// WithCompression wraps driver so that files written through it are stored
+// gzip/zstd-encoded in S3 (algo picks the default codec for names that don't
+// already carry a .gz or .zst suffix).
+func WithCompression(driver *S3Driver, algo CompressionAlgo) *CompressedDriver {
+	return &CompressedDriver{S3Driver: driver, algo: algo}
+}
+
+func (d *CompressedDriver) encodedPath(path string) (string, CompressionAlgo) {
+	if algo, _ := algoFromSuffix(path); algo != CompressionNone {
+		return path, algo
+	}
+	if d.algo == CompressionNone {
+		return path, CompressionNone
+	}
+	return path + d.algo.suffix(), d.algo
+}
+
+func (d *CompressedDriver) Stat(ctx context.Context, path string) (os.FileInfo, error) {
+	encodedPath, _ := d.encodedPath(path)
+	info, err := d.S3Driver.Stat(ctx, encodedPath)
+	if err != nil {
+		return nil, err
+	}
+	_, logicalName := algoFromSuffix(info.Name())
+	return &fileInfo{
+		name:  logicalName,
+		mode:  info.Mode(),
+		size:  info.Size(),
+		mtime: info.ModTime(),
+	}, nil
+}
+
+func (d *CompressedDriver) ListDir(ctx context.Context, path string) ([]os.FileInfo, error) {
+	files, err := d.S3Driver.ListDir(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	decoded := make([]os.FileInfo, len(files))
+	for i, f := range files {
+		_, logicalName := algoFromSuffix(f.Name())
+		decoded[i] = &fileInfo{
+			name:  logicalName,
+			mode:  f.Mode(),
+			size:  f.Size(),
+			mtime: f.ModTime(),
+		}
+	}
+	return decoded, nil
+}
+
+func (d *CompressedDriver) GetFile(ctx context.Context, path string) (io.ReadCloser, error) {
+	encodedPath, algo := d.encodedPath(path)
+	body, err := d.S3Driver.GetFile(ctx, encodedPath)
+	if err != nil {
+		return nil, err
+	}
+
+	switch algo {
+	case CompressionGzip:
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			body.Close()
+			return nil, err
+		}
+		return &decodingReadCloser{Reader: gz, underlying: body}, nil
+	case CompressionZstd:
+		zr, err := zstd.NewReader(body)
+		if err != nil {
+			body.Close()
+			return nil, err
+		}
+		return &decodingReadCloser{Reader: zr.IOReadCloser(), underlying: body}, nil
+	default:
+		return body, nil
+	}
+}
+
+func (d *CompressedDriver) PutFile(ctx context.Context, path string, r io.Reader) error {
+	encodedPath, algo := d.encodedPath(path)
+	if algo == CompressionNone {
+		return d.S3Driver.putFile(ctx, encodedPath, r, "")
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		switch algo {
+		case CompressionGzip:
+			w := gzip.NewWriter(pw)
+			_, err := io.Copy(w, r)
+			if closeErr := w.Close(); err == nil {
+				err = closeErr
+			}
+			pw.CloseWithError(err)
+		case CompressionZstd:
+			w, err := zstd.NewWriter(pw)
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			_, err = io.Copy(w, r)
+			if closeErr := w.Close(); err == nil {
+				err = closeErr
+			}
+			pw.CloseWithError(err)
+		}
+	}()
+
+	return d.S3Driver.putFile(ctx, encodedPath, pr, algo.contentEncoding())
+}
+
+// decodingReadCloser closes both the decompressing reader and the underlying
+// S3 object body it reads from. Closing the decoder matters beyond the
+// underlying body: for zstd in particular, Close tears down the decoder's
+// internal goroutine pool, so skipping it leaks goroutines on every
+// compressed GetFile.
+type decodingReadCloser struct {
+	io.Reader
+	underlying io.ReadCloser
+}
+
+func (d *decodingReadCloser) Close() error {
+	var err error
+	if c, ok := d.Reader.(io.Closer); ok {
+		err = c.Close()
+	}
+	if closeErr := d.underlying.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
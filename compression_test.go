@@ -0,0 +1,186 @@
+package sftp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/golang/mock/gomock"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlgoFromSuffix(t *testing.T) {
+	algo, name := algoFromSuffix("file.txt.gz")
+	assert.Equal(t, CompressionGzip, algo)
+	assert.Equal(t, "file.txt", name)
+
+	algo, name = algoFromSuffix("file.txt.zst")
+	assert.Equal(t, CompressionZstd, algo)
+	assert.Equal(t, "file.txt", name)
+
+	algo, name = algoFromSuffix("file.txt")
+	assert.Equal(t, CompressionNone, algo)
+	assert.Equal(t, "file.txt", name)
+}
+
+func TestEncodedPath(t *testing.T) {
+	d := &CompressedDriver{S3Driver: &S3Driver{}, algo: CompressionGzip}
+
+	path, algo := d.encodedPath("file.txt")
+	assert.Equal(t, "file.txt.gz", path)
+	assert.Equal(t, CompressionGzip, algo)
+
+	// Already-suffixed names are taken at face value, regardless of the
+	// driver's configured default codec.
+	path, algo = d.encodedPath("file.txt.zst")
+	assert.Equal(t, "file.txt.zst", path)
+	assert.Equal(t, CompressionZstd, algo)
+}
+
+func TestCompressedDriverStatStripsSuffix(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockS3API := NewMockS3API(mockCtrl)
+
+	mockS3API.EXPECT().ListObjectsV2(gomock.Any(), &s3.ListObjectsV2Input{
+		Bucket:  aws.String("bucket"),
+		Prefix:  aws.String("home/dir/file.txt.gz"),
+		MaxKeys: aws.Int32(1),
+	}).Return(&s3.ListObjectsV2Output{
+		KeyCount: aws.Int32(1),
+		Contents: []types.Object{{Key: aws.String("home/dir/file.txt.gz"), Size: aws.Int64(3), LastModified: aws.Time(time.Unix(0, 0))}},
+	}, nil)
+
+	d := WithCompression(&S3Driver{s3: mockS3API, bucket: "bucket", homePath: "home"}, CompressionGzip)
+	info, err := d.Stat(context.Background(), "dir/file.txt")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "home/dir/file.txt", info.Name())
+}
+
+func TestCompressedDriverListDirStripsSuffix(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockS3API := NewMockS3API(mockCtrl)
+
+	mockS3API.EXPECT().ListObjectsV2(gomock.Any(), &s3.ListObjectsV2Input{
+		Bucket:            aws.String("bucket"),
+		Prefix:            aws.String("home/dir/"),
+		Delimiter:         aws.String("/"),
+		ContinuationToken: (*string)(nil),
+	}).Return(&s3.ListObjectsV2Output{
+		Contents: []types.Object{{Key: aws.String("home/dir/file.txt.zst"), Size: aws.Int64(5), LastModified: aws.Time(time.Unix(0, 0))}},
+	}, nil)
+
+	d := WithCompression(&S3Driver{s3: mockS3API, bucket: "bucket", homePath: "home"}, CompressionZstd)
+	files, err := d.ListDir(context.Background(), "dir")
+
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, "file.txt", files[0].Name())
+}
+
+func TestCompressedDriverPutFileGetFileGzipRoundTrip(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockS3API := NewMockS3API(mockCtrl)
+
+	var stored bytes.Buffer
+	mockS3API.EXPECT().PutObject(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, in *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			assert.Equal(t, "home/dir/file.txt.gz", *in.Key)
+			assert.Equal(t, "gzip", *in.ContentEncoding)
+			_, err := io.Copy(&stored, in.Body)
+			return &s3.PutObjectOutput{}, err
+		},
+	)
+
+	d := WithCompression(&S3Driver{s3: mockS3API, bucket: "bucket", homePath: "home"}, CompressionGzip)
+	err := d.PutFile(context.Background(), "dir/file.txt", bytes.NewReader([]byte("hello compressed world")))
+	assert.NoError(t, err)
+
+	mockS3API.EXPECT().GetObject(gomock.Any(), &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("home/dir/file.txt.gz"),
+	}).Return(&s3.GetObjectOutput{
+		Body: io.NopCloser(bytes.NewReader(stored.Bytes())),
+	}, nil)
+
+	rc, err := d.GetFile(context.Background(), "dir/file.txt")
+	assert.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello compressed world", string(data))
+	assert.NoError(t, rc.Close())
+}
+
+// closeTrackingReadCloser records whether Close was called, so tests can
+// assert decodingReadCloser closes both the decoder and the underlying body.
+type closeTrackingReadCloser struct {
+	io.Reader
+	closed bool
+}
+
+func (c *closeTrackingReadCloser) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestDecodingReadCloserClosesDecoderAndBody(t *testing.T) {
+	underlying := &closeTrackingReadCloser{Reader: bytes.NewReader(nil)}
+	decoder := &closeTrackingReadCloser{Reader: bytes.NewReader(nil)}
+
+	d := &decodingReadCloser{Reader: decoder, underlying: underlying}
+	assert.NoError(t, d.Close())
+
+	assert.True(t, decoder.closed, "Close should close the decoder, not just the underlying body")
+	assert.True(t, underlying.closed)
+}
+
+func TestDecodingReadCloserZstdRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	zw, err := zstd.NewWriter(&buf)
+	assert.NoError(t, err)
+	_, err = zw.Write([]byte("hello zstd"))
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+
+	zr, err := zstd.NewReader(&buf)
+	assert.NoError(t, err)
+	underlying := &closeTrackingReadCloser{Reader: bytes.NewReader(nil)}
+	d := &decodingReadCloser{Reader: zr.IOReadCloser(), underlying: underlying}
+
+	data, err := io.ReadAll(d)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello zstd", string(data))
+
+	assert.NoError(t, d.Close())
+	assert.True(t, underlying.closed)
+}
+
+func TestDecodingReadCloserGzipRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	_, err := gw.Write([]byte("hello gzip"))
+	assert.NoError(t, err)
+	assert.NoError(t, gw.Close())
+
+	gr, err := gzip.NewReader(&buf)
+	assert.NoError(t, err)
+	underlying := &closeTrackingReadCloser{Reader: bytes.NewReader(nil)}
+	d := &decodingReadCloser{Reader: gr, underlying: underlying}
+
+	data, err := io.ReadAll(d)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello gzip", string(data))
+
+	assert.NoError(t, d.Close())
+	assert.True(t, underlying.closed)
+}
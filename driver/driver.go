@@ -0,0 +1,34 @@
+// Package driver defines the storage backend interface consumed by the SFTP
+// handlers, decoupling them from any one implementation (S3, local disk,
+// in-memory).
+package driver
+
+import (
+	"context"
+	"io"
+	"os"
+
+	sftp "github.com/Clever/sftp"
+)
+
+// StorageDriver is the method set the SFTP handlers need from a storage
+// backend. It's implemented by github.com/Clever/sftp.S3Driver as well as
+// the LocalDriver and InMemoryDriver in this package. Every method takes a
+// context so a client disconnect can abort whatever the backend is doing
+// in-flight instead of running it to completion regardless.
+type StorageDriver interface {
+	Stat(ctx context.Context, path string) (os.FileInfo, error)
+	ListDir(ctx context.Context, path string) ([]os.FileInfo, error)
+	DeleteDir(ctx context.Context, path string) error
+	DeleteFile(ctx context.Context, path string) error
+	Rename(ctx context.Context, oldpath, newpath string) error
+	MakeDir(ctx context.Context, path string) error
+	GetFile(ctx context.Context, path string) (io.ReadCloser, error)
+	PutFile(ctx context.Context, path string, r io.Reader) error
+}
+
+var (
+	_ StorageDriver = sftp.S3Driver{}
+	_ StorageDriver = (*LocalDriver)(nil)
+	_ StorageDriver = (*InMemoryDriver)(nil)
+)
@@ -0,0 +1,286 @@
+package driver
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	sftp "github.com/Clever/sftp"
+)
+
+// memFileInfo is the os.FileInfo implementation used by InMemoryDriver.
+type memFileInfo struct {
+	name  string
+	size  int64
+	mtime time.Time
+	isDir bool
+}
+
+func (fi *memFileInfo) Name() string       { return fi.name }
+func (fi *memFileInfo) Size() int64        { return fi.size }
+func (fi *memFileInfo) ModTime() time.Time { return fi.mtime }
+func (fi *memFileInfo) IsDir() bool        { return fi.isDir }
+func (fi *memFileInfo) Sys() interface{}   { return nil }
+func (fi *memFileInfo) Mode() os.FileMode {
+	if fi.isDir {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
+
+// InMemoryDriver is a StorageDriver backed by an in-process map, intended for
+// tests and CI where spinning up S3 or a scratch filesystem isn't worth it.
+type InMemoryDriver struct {
+	mu    sync.Mutex
+	files map[string][]byte
+	dirs  map[string]bool
+	mtime map[string]time.Time
+}
+
+// NewInMemoryDriver creates an empty InMemoryDriver.
+func NewInMemoryDriver() *InMemoryDriver {
+	return &InMemoryDriver{
+		files: map[string][]byte{},
+		dirs:  map[string]bool{"": true},
+		mtime: map[string]time.Time{},
+	}
+}
+
+func (d *InMemoryDriver) translate(path string) (string, error) {
+	return sftp.TranslatePath("", "", path)
+}
+
+// Every method below takes a context to satisfy StorageDriver, but map
+// access here is never blocking, so it's unused.
+
+func (d *InMemoryDriver) Stat(ctx context.Context, path string) (os.FileInfo, error) {
+	key, err := d.translate(path)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.dirs[key] {
+		return &memFileInfo{name: key, isDir: true}, nil
+	}
+	if data, ok := d.files[key]; ok {
+		return &memFileInfo{name: key, size: int64(len(data)), mtime: d.mtime[key]}, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+func (d *InMemoryDriver) ListDir(ctx context.Context, path string) ([]os.FileInfo, error) {
+	prefix, err := d.translate(path)
+	if err != nil {
+		return nil, err
+	}
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	seen := map[string]bool{}
+	var infos []os.FileInfo
+	for name, data := range d.files {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(name, prefix)
+		if idx := strings.Index(rest, "/"); idx >= 0 {
+			childDir := rest[:idx]
+			if !seen[childDir] {
+				seen[childDir] = true
+				infos = append(infos, &memFileInfo{name: childDir, isDir: true})
+			}
+			continue
+		}
+		infos = append(infos, &memFileInfo{name: rest, size: int64(len(data)), mtime: d.mtime[name]})
+	}
+	for name := range d.dirs {
+		if name == prefix || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(name, prefix)
+		if idx := strings.Index(rest, "/"); idx >= 0 {
+			rest = rest[:idx]
+		}
+		if rest != "" && !seen[rest] {
+			seen[rest] = true
+			infos = append(infos, &memFileInfo{name: rest, isDir: true})
+		}
+	}
+	return infos, nil
+}
+
+func (d *InMemoryDriver) DeleteDir(ctx context.Context, path string) error {
+	key, err := d.translate(path)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.dirs[key] {
+		return os.ErrNotExist
+	}
+	delete(d.dirs, key)
+	return nil
+}
+
+func (d *InMemoryDriver) DeleteFile(ctx context.Context, path string) error {
+	key, err := d.translate(path)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.files[key]; !ok {
+		return os.ErrNotExist
+	}
+	delete(d.files, key)
+	delete(d.mtime, key)
+	return nil
+}
+
+// Rename moves oldpath to newpath. If oldpath names a single file, only that
+// file moves; if it names a directory, the whole tree underneath it moves,
+// matching S3Driver and LocalDriver. The root directory itself can't be
+// renamed. Like S3Driver, it doesn't check whether newpath is already
+// occupied by an entry of the other kind (file vs. directory) before
+// overwriting it.
+func (d *InMemoryDriver) Rename(ctx context.Context, oldpath, newpath string) error {
+	oldKey, err := d.translate(oldpath)
+	if err != nil {
+		return err
+	}
+	newKey, err := d.translate(newpath)
+	if err != nil {
+		return err
+	}
+
+	// SFTP clients commonly send a trailing slash for directory renames;
+	// strip it so it doesn't keep oldKey/newKey from matching the keys
+	// MakeDir etc. actually stored.
+	oldKey = strings.TrimSuffix(oldKey, "/")
+	newKey = strings.TrimSuffix(newKey, "/")
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if oldKey == newKey {
+		if _, ok := d.files[oldKey]; ok {
+			return nil
+		}
+		if d.dirs[oldKey] {
+			return nil
+		}
+		return os.ErrNotExist
+	}
+
+	if oldKey == "" || newKey == "" {
+		return fmt.Errorf("cannot rename the root directory")
+	}
+
+	if data, ok := d.files[oldKey]; ok {
+		d.files[newKey] = data
+		d.mtime[newKey] = d.mtime[oldKey]
+		delete(d.files, oldKey)
+		delete(d.mtime, oldKey)
+		return nil
+	}
+
+	if !d.dirs[oldKey] {
+		return os.ErrNotExist
+	}
+
+	oldPrefix := oldKey + "/"
+	newPrefix := newKey + "/"
+	if strings.HasPrefix(newKey, oldPrefix) {
+		return fmt.Errorf("cannot rename %q into its own subdirectory %q", oldpath, newpath)
+	}
+
+	// Collect matching keys before mutating the maps; inserting new keys
+	// while ranging over the ones being renamed is undefined behavior.
+	var fileNames, dirNames []string
+	for name := range d.files {
+		if strings.HasPrefix(name, oldPrefix) {
+			fileNames = append(fileNames, name)
+		}
+	}
+	for name := range d.dirs {
+		if name == oldKey || strings.HasPrefix(name, oldPrefix) {
+			dirNames = append(dirNames, name)
+		}
+	}
+
+	for _, name := range fileNames {
+		newName := newPrefix + strings.TrimPrefix(name, oldPrefix)
+		d.files[newName] = d.files[name]
+		d.mtime[newName] = d.mtime[name]
+		delete(d.files, name)
+		delete(d.mtime, name)
+	}
+	for _, name := range dirNames {
+		newName := newKey
+		if name != oldKey {
+			newName = newPrefix + strings.TrimPrefix(name, oldPrefix)
+		}
+		d.dirs[newName] = true
+		delete(d.dirs, name)
+	}
+	return nil
+}
+
+func (d *InMemoryDriver) MakeDir(ctx context.Context, path string) error {
+	key, err := d.translate(path)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.dirs[key] = true
+	return nil
+}
+
+func (d *InMemoryDriver) GetFile(ctx context.Context, path string) (io.ReadCloser, error) {
+	key, err := d.translate(path)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	data, ok := d.files[key]
+	d.mu.Unlock()
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (d *InMemoryDriver) PutFile(ctx context.Context, path string, r io.Reader) error {
+	key, err := d.translate(path)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.files[key] = data
+	d.mtime[key] = time.Now()
+	return nil
+}
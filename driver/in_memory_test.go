@@ -0,0 +1,196 @@
+package driver
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInMemoryDriverPutGetFile(t *testing.T) {
+	d := NewInMemoryDriver()
+
+	assert.NoError(t, d.PutFile(context.Background(), "dir/file", bytes.NewReader([]byte("hello"))))
+
+	rc, err := d.GetFile(context.Background(), "dir/file")
+	assert.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	info, err := d.Stat(context.Background(), "dir/file")
+	assert.NoError(t, err)
+	assert.Equal(t, "dir/file", info.Name())
+	assert.False(t, info.IsDir())
+}
+
+func TestInMemoryDriverListDir(t *testing.T) {
+	d := NewInMemoryDriver()
+	assert.NoError(t, d.PutFile(context.Background(), "dir/a", bytes.NewReader(nil)))
+	assert.NoError(t, d.PutFile(context.Background(), "dir/b", bytes.NewReader(nil)))
+	assert.NoError(t, d.MakeDir(context.Background(), "dir/nested"))
+
+	files, err := d.ListDir(context.Background(), "dir")
+	assert.NoError(t, err)
+	assert.Len(t, files, 3)
+}
+
+func TestInMemoryDriverDeleteAndRename(t *testing.T) {
+	d := NewInMemoryDriver()
+	assert.NoError(t, d.PutFile(context.Background(), "file", bytes.NewReader([]byte("x"))))
+
+	assert.NoError(t, d.Rename(context.Background(), "file", "renamed"))
+	_, err := d.Stat(context.Background(), "file")
+	assert.Error(t, err)
+
+	info, err := d.Stat(context.Background(), "renamed")
+	assert.NoError(t, err)
+	assert.Equal(t, "renamed", info.Name())
+
+	assert.NoError(t, d.DeleteFile(context.Background(), "renamed"))
+	_, err = d.Stat(context.Background(), "renamed")
+	assert.Error(t, err)
+}
+
+func TestInMemoryDriverRenameDirectory(t *testing.T) {
+	d := NewInMemoryDriver()
+	assert.NoError(t, d.MakeDir(context.Background(), "dir"))
+	assert.NoError(t, d.PutFile(context.Background(), "dir/file", bytes.NewReader([]byte("a"))))
+	assert.NoError(t, d.MakeDir(context.Background(), "dir/nested"))
+	assert.NoError(t, d.PutFile(context.Background(), "dir/nested/file", bytes.NewReader([]byte("b"))))
+
+	assert.NoError(t, d.Rename(context.Background(), "dir", "renamed"))
+
+	_, err := d.Stat(context.Background(), "dir")
+	assert.Error(t, err)
+
+	info, err := d.Stat(context.Background(), "renamed")
+	assert.NoError(t, err)
+	assert.True(t, info.IsDir())
+
+	info, err = d.Stat(context.Background(), "renamed/nested")
+	assert.NoError(t, err)
+	assert.True(t, info.IsDir())
+
+	rc, err := d.GetFile(context.Background(), "renamed/file")
+	assert.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", string(data))
+
+	rc, err = d.GetFile(context.Background(), "renamed/nested/file")
+	assert.NoError(t, err)
+	data, err = io.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, "b", string(data))
+}
+
+func TestInMemoryDriverRenameDirectoryTrailingSlash(t *testing.T) {
+	d := NewInMemoryDriver()
+	assert.NoError(t, d.MakeDir(context.Background(), "dir"))
+	assert.NoError(t, d.PutFile(context.Background(), "dir/file", bytes.NewReader([]byte("a"))))
+
+	assert.NoError(t, d.Rename(context.Background(), "dir/", "renamed/"))
+
+	_, err := d.Stat(context.Background(), "dir")
+	assert.Error(t, err)
+
+	rc, err := d.GetFile(context.Background(), "renamed/file")
+	assert.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", string(data))
+}
+
+func TestInMemoryDriverRenameDirectoryToItself(t *testing.T) {
+	d := NewInMemoryDriver()
+	assert.NoError(t, d.MakeDir(context.Background(), "dir"))
+	assert.NoError(t, d.PutFile(context.Background(), "dir/file", bytes.NewReader([]byte("a"))))
+
+	assert.NoError(t, d.Rename(context.Background(), "dir", "dir"))
+
+	rc, err := d.GetFile(context.Background(), "dir/file")
+	assert.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", string(data))
+}
+
+func TestInMemoryDriverRenameDirectoryIntoOwnSubdirectory(t *testing.T) {
+	d := NewInMemoryDriver()
+	assert.NoError(t, d.MakeDir(context.Background(), "dir"))
+	assert.NoError(t, d.MakeDir(context.Background(), "dir/sub"))
+	assert.NoError(t, d.PutFile(context.Background(), "dir/sub/file", bytes.NewReader([]byte("a"))))
+
+	err := d.Rename(context.Background(), "dir", "dir/sub")
+	assert.Error(t, err)
+
+	rc, err := d.GetFile(context.Background(), "dir/sub/file")
+	assert.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", string(data))
+}
+
+func TestInMemoryDriverRenameNonexistentToItself(t *testing.T) {
+	d := NewInMemoryDriver()
+
+	err := d.Rename(context.Background(), "ghost", "ghost")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestInMemoryDriverRenameRootNotAllowed(t *testing.T) {
+	d := NewInMemoryDriver()
+	assert.NoError(t, d.PutFile(context.Background(), "file", bytes.NewReader([]byte("a"))))
+
+	err := d.Rename(context.Background(), "", "backup")
+	assert.Error(t, err)
+
+	info, err := d.Stat(context.Background(), "")
+	assert.NoError(t, err)
+	assert.True(t, info.IsDir())
+}
+
+func TestInMemoryDriverRenameFileIntoRootNotAllowed(t *testing.T) {
+	d := NewInMemoryDriver()
+	assert.NoError(t, d.PutFile(context.Background(), "file.txt", bytes.NewReader([]byte("a"))))
+
+	err := d.Rename(context.Background(), "file.txt", "")
+	assert.Error(t, err)
+
+	info, err := d.Stat(context.Background(), "")
+	assert.NoError(t, err)
+	assert.True(t, info.IsDir())
+
+	rc, err := d.GetFile(context.Background(), "file.txt")
+	assert.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", string(data))
+}
+
+func TestInMemoryDriverRenameIntoRootNotAllowed(t *testing.T) {
+	d := NewInMemoryDriver()
+	assert.NoError(t, d.MakeDir(context.Background(), "dir"))
+	assert.NoError(t, d.PutFile(context.Background(), "dir/file", bytes.NewReader([]byte("a"))))
+
+	err := d.Rename(context.Background(), "dir", "")
+	assert.Error(t, err)
+
+	rc, err := d.GetFile(context.Background(), "dir/file")
+	assert.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", string(data))
+}
+
+func TestInMemoryDriverEscapePrevention(t *testing.T) {
+	d := NewInMemoryDriver()
+	assert.NoError(t, d.PutFile(context.Background(), "../../etc/passwd", bytes.NewReader([]byte("x"))))
+
+	_, err := d.GetFile(context.Background(), "etc/passwd")
+	assert.NoError(t, err)
+}
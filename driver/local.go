@@ -0,0 +1,119 @@
+package driver
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+
+	sftp "github.com/Clever/sftp"
+)
+
+// LocalDriver is a StorageDriver backed by the OS filesystem, chrooted to a
+// home directory on disk. It reuses sftp.TranslatePath's escape prevention so
+// a client can't read or write outside of root via "..".
+type LocalDriver struct {
+	root string
+}
+
+// NewLocalDriver creates a LocalDriver rooted at root. root must already exist.
+func NewLocalDriver(root string) *LocalDriver {
+	return &LocalDriver{root: root}
+}
+
+func (d *LocalDriver) translate(path string) (string, error) {
+	relPath, err := sftp.TranslatePath("", "", path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(d.root, relPath), nil
+}
+
+// Every method below takes a context to satisfy StorageDriver, but the os
+// package has no way to cancel a filesystem call mid-flight, so it's unused.
+
+func (d *LocalDriver) Stat(ctx context.Context, path string) (os.FileInfo, error) {
+	localPath, err := d.translate(path)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(localPath)
+}
+
+func (d *LocalDriver) ListDir(ctx context.Context, path string) ([]os.FileInfo, error) {
+	localPath, err := d.translate(path)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(localPath)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (d *LocalDriver) DeleteDir(ctx context.Context, path string) error {
+	localPath, err := d.translate(path)
+	if err != nil {
+		return err
+	}
+	return os.Remove(localPath)
+}
+
+func (d *LocalDriver) DeleteFile(ctx context.Context, path string) error {
+	localPath, err := d.translate(path)
+	if err != nil {
+		return err
+	}
+	return os.Remove(localPath)
+}
+
+func (d *LocalDriver) Rename(ctx context.Context, oldpath, newpath string) error {
+	localOldpath, err := d.translate(oldpath)
+	if err != nil {
+		return err
+	}
+	localNewpath, err := d.translate(newpath)
+	if err != nil {
+		return err
+	}
+	return os.Rename(localOldpath, localNewpath)
+}
+
+func (d *LocalDriver) MakeDir(ctx context.Context, path string) error {
+	localPath, err := d.translate(path)
+	if err != nil {
+		return err
+	}
+	return os.Mkdir(localPath, 0755)
+}
+
+func (d *LocalDriver) GetFile(ctx context.Context, path string) (io.ReadCloser, error) {
+	localPath, err := d.translate(path)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(localPath)
+}
+
+func (d *LocalDriver) PutFile(ctx context.Context, path string, r io.Reader) error {
+	localPath, err := d.translate(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(f, r)
+	return err
+}
@@ -0,0 +1,105 @@
+package driver
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalDriverPutGetFile(t *testing.T) {
+	d := NewLocalDriver(t.TempDir())
+
+	assert.NoError(t, d.MakeDir(context.Background(), "dir"))
+	assert.NoError(t, d.PutFile(context.Background(), "dir/file", bytes.NewReader([]byte("hello"))))
+
+	rc, err := d.GetFile(context.Background(), "dir/file")
+	assert.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+	assert.NoError(t, rc.Close())
+
+	info, err := d.Stat(context.Background(), "dir/file")
+	assert.NoError(t, err)
+	assert.False(t, info.IsDir())
+}
+
+func TestLocalDriverListDir(t *testing.T) {
+	d := NewLocalDriver(t.TempDir())
+	assert.NoError(t, d.MakeDir(context.Background(), "dir"))
+	assert.NoError(t, d.PutFile(context.Background(), "dir/a", bytes.NewReader(nil)))
+	assert.NoError(t, d.PutFile(context.Background(), "dir/b", bytes.NewReader(nil)))
+	assert.NoError(t, d.MakeDir(context.Background(), "dir/nested"))
+
+	files, err := d.ListDir(context.Background(), "dir")
+	assert.NoError(t, err)
+	assert.Len(t, files, 3)
+}
+
+func TestLocalDriverDeleteAndRename(t *testing.T) {
+	d := NewLocalDriver(t.TempDir())
+	assert.NoError(t, d.PutFile(context.Background(), "file", bytes.NewReader([]byte("x"))))
+
+	assert.NoError(t, d.Rename(context.Background(), "file", "renamed"))
+	_, err := d.Stat(context.Background(), "file")
+	assert.Error(t, err)
+
+	info, err := d.Stat(context.Background(), "renamed")
+	assert.NoError(t, err)
+	assert.False(t, info.IsDir())
+
+	assert.NoError(t, d.DeleteFile(context.Background(), "renamed"))
+	_, err = d.Stat(context.Background(), "renamed")
+	assert.Error(t, err)
+}
+
+func TestLocalDriverRenameDirectory(t *testing.T) {
+	d := NewLocalDriver(t.TempDir())
+	assert.NoError(t, d.MakeDir(context.Background(), "dir"))
+	assert.NoError(t, d.PutFile(context.Background(), "dir/file", bytes.NewReader([]byte("a"))))
+
+	assert.NoError(t, d.Rename(context.Background(), "dir", "renamed"))
+
+	_, err := d.Stat(context.Background(), "dir")
+	assert.Error(t, err)
+
+	rc, err := d.GetFile(context.Background(), "renamed/file")
+	assert.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, "a", string(data))
+	assert.NoError(t, rc.Close())
+}
+
+func TestLocalDriverRenameIntoOwnSubdirectoryFails(t *testing.T) {
+	d := NewLocalDriver(t.TempDir())
+	assert.NoError(t, d.MakeDir(context.Background(), "dir"))
+	assert.NoError(t, d.MakeDir(context.Background(), "dir/sub"))
+
+	err := d.Rename(context.Background(), "dir", "dir/sub")
+	assert.Error(t, err)
+}
+
+func TestLocalDriverEscapePrevention(t *testing.T) {
+	root := t.TempDir()
+	d := NewLocalDriver(root)
+
+	assert.NoError(t, d.MakeDir(context.Background(), "../../etc"))
+	assert.NoError(t, d.PutFile(context.Background(), "../../etc/passwd", bytes.NewReader([]byte("x"))))
+
+	// The write has to land under root, not actually at /etc/passwd.
+	_, err := os.Stat(filepath.Join(root, "etc/passwd"))
+	assert.NoError(t, err)
+
+	rc, err := d.GetFile(context.Background(), "etc/passwd")
+	assert.NoError(t, err)
+	data, err := io.ReadAll(rc)
+	assert.NoError(t, err)
+	assert.Equal(t, "x", string(data))
+	assert.NoError(t, rc.Close())
+}
@@ -0,0 +1,22 @@
+package sftp
+
+import (
+	"os"
+	"time"
+)
+
+// fileInfo is the os.FileInfo implementation returned by S3Driver's Stat and
+// ListDir, since S3 objects don't carry that information natively.
+type fileInfo struct {
+	name  string
+	mode  os.FileMode
+	size  int64
+	mtime time.Time
+}
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) Mode() os.FileMode  { return fi.mode }
+func (fi *fileInfo) ModTime() time.Time { return fi.mtime }
+func (fi *fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *fileInfo) Sys() interface{}   { return nil }
@@ -0,0 +1,150 @@
+package sftp
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeS3 is a hand-rolled stand-in for the S3API interface, used by the
+// Rename tests below. Unlike the generated mock used elsewhere in this
+// package, it models a tiny in-memory bucket so multi-call sequences (list,
+// then copy each key, then batch-delete) can be exercised without
+// pre-scripting every call.
+type fakeS3 struct {
+	objects        map[string]int64 // key -> size
+	copyErr        error
+	uploadPartErr  error
+	deletedKeys    []string
+	copiedSources  []string
+	copiedDests    []string
+	abortedUploads int
+}
+
+func newFakeS3(objects map[string]int64) *fakeS3 {
+	return &fakeS3{objects: objects}
+}
+
+func (f *fakeS3) ListObjectsV2(ctx context.Context, in *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	var contents []types.Object
+	for key, size := range f.objects {
+		if in.MaxKeys != nil && len(contents) == int(*in.MaxKeys) {
+			break
+		}
+		if in.Prefix == nil || len(key) >= len(*in.Prefix) && key[:len(*in.Prefix)] == *in.Prefix {
+			contents = append(contents, types.Object{Key: aws.String(key), Size: aws.Int64(size)})
+		}
+	}
+	return &s3.ListObjectsV2Output{
+		Contents:    contents,
+		KeyCount:    aws.Int32(int32(len(contents))),
+		IsTruncated: aws.Bool(false),
+	}, nil
+}
+
+func (f *fakeS3) DeleteObject(ctx context.Context, in *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	f.deletedKeys = append(f.deletedKeys, *in.Key)
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (f *fakeS3) DeleteObjects(ctx context.Context, in *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	for _, obj := range in.Delete.Objects {
+		f.deletedKeys = append(f.deletedKeys, *obj.Key)
+	}
+	return &s3.DeleteObjectsOutput{}, nil
+}
+
+func (f *fakeS3) CopyObject(ctx context.Context, in *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	if f.copyErr != nil {
+		return nil, f.copyErr
+	}
+	f.copiedSources = append(f.copiedSources, *in.CopySource)
+	f.copiedDests = append(f.copiedDests, *in.Key)
+	return &s3.CopyObjectOutput{}, nil
+}
+
+func (f *fakeS3) PutObject(ctx context.Context, in *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *fakeS3) GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	return &s3.GetObjectOutput{}, nil
+}
+
+func (f *fakeS3) CreateMultipartUpload(ctx context.Context, in *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	return &s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-id")}, nil
+}
+
+func (f *fakeS3) UploadPart(ctx context.Context, in *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	return &s3.UploadPartOutput{ETag: aws.String("etag")}, nil
+}
+
+func (f *fakeS3) UploadPartCopy(ctx context.Context, in *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	if f.uploadPartErr != nil {
+		return nil, f.uploadPartErr
+	}
+	f.copiedSources = append(f.copiedSources, *in.CopySource)
+	f.copiedDests = append(f.copiedDests, *in.Key)
+	return &s3.UploadPartCopyOutput{
+		CopyPartResult: &types.CopyPartResult{ETag: aws.String("etag")},
+	}, nil
+}
+
+func (f *fakeS3) CompleteMultipartUpload(ctx context.Context, in *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	return &s3.CompleteMultipartUploadOutput{}, nil
+}
+
+func (f *fakeS3) AbortMultipartUpload(ctx context.Context, in *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	f.abortedUploads++
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+func TestRenameDirectory(t *testing.T) {
+	fake := newFakeS3(map[string]int64{
+		"home/dir/file":        10,
+		"home/dir/nested/file": 20,
+	})
+	driver := &S3Driver{s3: fake, bucket: "bucket", homePath: "home"}
+
+	err := driver.Rename(context.Background(), "dir", "renamed")
+
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"home/renamed/file", "home/renamed/nested/file"}, fake.copiedDests)
+	assert.ElementsMatch(t, []string{"home/dir/file", "home/dir/nested/file"}, fake.deletedKeys)
+}
+
+func TestRenameLargeSingleObjectUsesMultipartCopy(t *testing.T) {
+	const size = multipartCopyThreshold + 1
+	fake := newFakeS3(map[string]int64{
+		"home/dir/big_file": size,
+	})
+	driver := &S3Driver{s3: fake, bucket: "bucket", homePath: "home", partSize: defaultPartSize}
+
+	err := driver.Rename(context.Background(), "dir/big_file", "dir/renamed_file")
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, fake.copiedDests)
+	for _, dest := range fake.copiedDests {
+		assert.Equal(t, "home/dir/renamed_file", dest)
+	}
+	assert.Equal(t, []string{"home/dir/big_file"}, fake.deletedKeys)
+	assert.Zero(t, fake.abortedUploads)
+}
+
+func TestRenameDirectoryRollsBackOnPartialCopyFailure(t *testing.T) {
+	fake := newFakeS3(map[string]int64{
+		"home/dir/file":        10,
+		"home/dir/nested/file": 20,
+	})
+	fake.copyErr = assert.AnError
+	driver := &S3Driver{s3: fake, bucket: "bucket", homePath: "home"}
+
+	err := driver.Rename(context.Background(), "dir", "renamed")
+
+	assert.Error(t, err)
+	assert.Empty(t, fake.deletedKeys)
+}
@@ -2,44 +2,97 @@ package sftp
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	"io"
-	"io/ioutil"
+	"net"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/credentials"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	stscredsv2 "github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 )
 
-type S3 interface {
-	ListObjectsV2(input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error)
-	DeleteObject(input *s3.DeleteObjectInput) (*s3.DeleteObjectOutput, error)
-	CopyObject(input *s3.CopyObjectInput) (*s3.CopyObjectOutput, error)
-	PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error)
-	GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error)
+// defaultPartSize and defaultUploadConcurrency govern PutFile's multipart upload
+// behavior when the driver isn't constructed with WithPartSize / WithUploadConcurrency.
+const (
+	defaultPartSize          = 8 * 1024 * 1024
+	defaultUploadConcurrency = 4
+
+	// multipartCopyThreshold is the largest object CopyObject can handle in a
+	// single call; anything bigger must go through UploadPartCopy instead.
+	multipartCopyThreshold = 5 * 1024 * 1024 * 1024
+
+	// deleteObjectsBatchSize is the largest number of keys DeleteObjects accepts
+	// in a single call.
+	deleteObjectsBatchSize = 1000
+)
+
+// BLOCK_DOWNLOADS_IP_ADDRESSES is a runtime-configurable deny-list of client
+// IPs that GetFile refuses to serve, so an abusive downloader can be cut off
+// without a deploy.
+var BLOCK_DOWNLOADS_IP_ADDRESSES []string
+
+// Logger is the structured logger S3Driver uses to record things like
+// blocked downloads. It's satisfied by the daemon's request logger.
+type Logger interface {
+	InfoD(title string, meta map[string]interface{})
+	ErrorD(title string, meta map[string]interface{})
+}
+
+// S3API is the subset of the aws-sdk-go-v2 S3 client that S3Driver depends
+// on, so tests can supply a mock instead of a real client.
+type S3API interface {
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
 }
 
 type S3Driver struct {
-	s3       S3
-	bucket   string
-	prefix   string
-	homePath string
+	s3                S3API
+	bucket            string
+	prefix            string
+	homePath          string
+	partSize          int64
+	uploadConcurrency int
+
+	// kmsKeyID, if set, encrypts objects this driver writes with the given
+	// SSE-KMS key instead of the default SSE-S3 (AES256).
+	kmsKeyID *string
+
+	// remoteIPAddress is the connecting SFTP client's address ("host:port"),
+	// used to enforce BLOCK_DOWNLOADS_IP_ADDRESSES. lg logs blocked attempts.
+	remoteIPAddress string
+	lg              Logger
 }
 
-func (d S3Driver) Stat(path string) (os.FileInfo, error) {
+func (d S3Driver) Stat(ctx context.Context, path string) (os.FileInfo, error) {
 	localPath, err := TranslatePath(d.prefix, d.homePath, path)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := d.s3.ListObjectsV2(&s3.ListObjectsV2Input{
+	resp, err := d.s3.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
 		Bucket:  aws.String(d.bucket),
 		Prefix:  aws.String(localPath),
-		MaxKeys: aws.Int64(1),
+		MaxKeys: aws.Int32(1),
 	})
 	if err != nil {
 		return nil, err
@@ -62,7 +115,7 @@ func (d S3Driver) Stat(path string) (os.FileInfo, error) {
 	return info, nil
 }
 
-func (d S3Driver) ListDir(path string) ([]os.FileInfo, error) {
+func (d S3Driver) ListDir(ctx context.Context, path string) ([]os.FileInfo, error) {
 	prefix, err := TranslatePath(d.prefix, d.homePath, path)
 	if err != nil {
 		return nil, err
@@ -73,7 +126,7 @@ func (d S3Driver) ListDir(path string) ([]os.FileInfo, error) {
 	var nextContinuationToken *string
 	files := []os.FileInfo{}
 	for {
-		objects, err := d.s3.ListObjectsV2(&s3.ListObjectsV2Input{
+		objects, err := d.s3.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
 			Bucket:            aws.String(d.bucket),
 			Prefix:            aws.String(prefix),
 			Delimiter:         aws.String("/"),
@@ -102,38 +155,49 @@ func (d S3Driver) ListDir(path string) ([]os.FileInfo, error) {
 			})
 		}
 
-		if !*objects.IsTruncated {
+		if objects.IsTruncated == nil || !*objects.IsTruncated {
 			return files, nil
 		}
 		nextContinuationToken = objects.NextContinuationToken
 	}
 }
 
-func (d S3Driver) DeleteDir(path string) error {
+func (d S3Driver) DeleteDir(ctx context.Context, path string) error {
 	translatedPath, err := TranslatePath(d.prefix, d.homePath, path)
 	if err != nil {
 		return err
 	}
-	_, err = d.s3.DeleteObject(&s3.DeleteObjectInput{
+	// Directories are represented as zero-byte keys with a trailing slash
+	// (see MakeDir), so the key deleted here has to carry one too or the
+	// directory marker is left behind.
+	if !strings.HasSuffix(translatedPath, "/") {
+		translatedPath += "/"
+	}
+	_, err = d.s3.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(d.bucket),
 		Key:    aws.String(translatedPath),
 	})
 	return err
 }
 
-func (d S3Driver) DeleteFile(path string) error {
+func (d S3Driver) DeleteFile(ctx context.Context, path string) error {
 	translatedPath, err := TranslatePath(d.prefix, d.homePath, path)
 	if err != nil {
 		return err
 	}
-	_, err = d.s3.DeleteObject(&s3.DeleteObjectInput{
+	_, err = d.s3.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(d.bucket),
 		Key:    aws.String(translatedPath),
 	})
 	return err
 }
 
-func (d S3Driver) Rename(oldpath string, newpath string) error {
+// Rename moves oldpath to newpath. If oldpath is a directory (a prefix under
+// which other objects live), every key under it is copied to the
+// corresponding key under newpath before any originals are deleted, so a
+// failure partway through leaves both the untouched originals and whatever
+// copies already succeeded, rather than a half-renamed tree.
+func (d S3Driver) Rename(ctx context.Context, oldpath string, newpath string) error {
 	translatedOldpath, err := TranslatePath(d.prefix, d.homePath, oldpath)
 	if err != nil {
 		return err
@@ -143,26 +207,204 @@ func (d S3Driver) Rename(oldpath string, newpath string) error {
 		return err
 	}
 
-	if _, err := d.s3.CopyObject(&s3.CopyObjectInput{
-		Bucket:               aws.String(d.bucket),
-		CopySource:           aws.String(d.bucket + "/" + translatedOldpath),
-		Key:                  &translatedNewpath,
-		ServerSideEncryption: aws.String("AES256"),
-	}); err != nil {
+	dirPrefix := strings.TrimSuffix(translatedOldpath, "/") + "/"
+	dirObjects, err := d.listAllKeys(ctx, dirPrefix)
+	if err != nil {
 		return err
 	}
 
-	if _, err = d.s3.DeleteObject(&s3.DeleteObjectInput{
+	if len(dirObjects) == 0 {
+		return d.renameObject(ctx, translatedOldpath, translatedNewpath)
+	}
+
+	newDirPrefix := strings.TrimSuffix(translatedNewpath, "/") + "/"
+	copiedKeys := make([]string, 0, len(dirObjects))
+	for _, obj := range dirObjects {
+		destKey := newDirPrefix + strings.TrimPrefix(*obj.Key, dirPrefix)
+		if err := d.copyObject(ctx, *obj.Key, destKey, *obj.Size); err != nil {
+			return err
+		}
+		copiedKeys = append(copiedKeys, *obj.Key)
+	}
+	return d.deleteObjects(ctx, copiedKeys)
+}
+
+// listAllKeys returns every object under prefix, following continuation
+// tokens until the listing is exhausted.
+func (d S3Driver) listAllKeys(ctx context.Context, prefix string) ([]types.Object, error) {
+	var keys []types.Object
+	var continuationToken *string
+	for {
+		out, err := d.s3.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(d.bucket),
+			Prefix:            aws.String(prefix),
+			ContinuationToken: continuationToken,
+		})
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, out.Contents...)
+		if out.IsTruncated == nil || !*out.IsTruncated {
+			return keys, nil
+		}
+		continuationToken = out.NextContinuationToken
+	}
+}
+
+// renameObject renames a single (non-directory) key, via UploadPartCopy when
+// the object is too large for a single-shot CopyObject.
+func (d S3Driver) renameObject(ctx context.Context, oldKey, newKey string) error {
+	size, err := d.objectSize(ctx, oldKey)
+	if err != nil {
+		return err
+	}
+	if err := d.copyObject(ctx, oldKey, newKey, size); err != nil {
+		return err
+	}
+	_, err = d.s3.DeleteObject(ctx, &s3.DeleteObjectInput{
 		Bucket: aws.String(d.bucket),
-		Key:    &translatedOldpath,
+		Key:    aws.String(oldKey),
+	})
+	return err
+}
+
+// objectSize returns the size of the object at key via ListObjectsV2, since
+// the S3API interface has no HeadObject.
+func (d S3Driver) objectSize(ctx context.Context, key string) (int64, error) {
+	out, err := d.s3.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket:  aws.String(d.bucket),
+		Prefix:  aws.String(key),
+		MaxKeys: aws.Int32(1),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if out.Contents == nil || *out.KeyCount == 0 || *out.Contents[0].Key != key {
+		return 0, os.ErrNotExist
+	}
+	return *out.Contents[0].Size, nil
+}
+
+// serverSideEncryption returns the SSE algorithm and (if using SSE-KMS) key
+// ID this driver writes objects with.
+func (d S3Driver) serverSideEncryption() (types.ServerSideEncryption, *string) {
+	if d.kmsKeyID != nil {
+		return types.ServerSideEncryptionAwsKms, d.kmsKeyID
+	}
+	return types.ServerSideEncryptionAes256, nil
+}
+
+// copyObject copies srcKey to destKey, falling back to a multipart
+// UploadPartCopy for objects larger than CopyObject's 5 GiB limit.
+func (d S3Driver) copyObject(ctx context.Context, srcKey, destKey string, size int64) error {
+	if size > multipartCopyThreshold {
+		return d.multipartCopy(ctx, srcKey, destKey, size)
+	}
+	sse, kmsKeyID := d.serverSideEncryption()
+	_, err := d.s3.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:               aws.String(d.bucket),
+		CopySource:           aws.String(d.bucket + "/" + srcKey),
+		Key:                  aws.String(destKey),
+		ServerSideEncryption: sse,
+		SSEKMSKeyId:          kmsKeyID,
+	})
+	return err
+}
+
+func (d S3Driver) multipartCopy(ctx context.Context, srcKey, destKey string, size int64) error {
+	sse, kmsKeyID := d.serverSideEncryption()
+	created, err := d.s3.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:               aws.String(d.bucket),
+		Key:                  aws.String(destKey),
+		ServerSideEncryption: sse,
+		SSEKMSKeyId:          kmsKeyID,
+	})
+	if err != nil {
+		return err
+	}
+	uploadID := created.UploadId
+	abort := func() {
+		// Deliberately not ctx; see the equivalent abort in putFile.
+		if _, err := d.s3.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(d.bucket),
+			Key:      aws.String(destKey),
+			UploadId: uploadID,
+		}); err != nil && d.lg != nil {
+			d.lg.ErrorD("s3-driver-abort-multipart-upload-failed", map[string]interface{}{
+				"key":   destKey,
+				"error": err.Error(),
+			})
+		}
+	}
+
+	partSize := d.partSize
+	if partSize == 0 {
+		partSize = defaultPartSize
+	}
+
+	var completedParts []types.CompletedPart
+	var partNumber int32 = 1
+	for start := int64(0); start < size; start += partSize {
+		end := start + partSize - 1
+		if end >= size {
+			end = size - 1
+		}
+		out, err := d.s3.UploadPartCopy(ctx, &s3.UploadPartCopyInput{
+			Bucket:          aws.String(d.bucket),
+			Key:             aws.String(destKey),
+			UploadId:        uploadID,
+			PartNumber:      aws.Int32(partNumber),
+			CopySource:      aws.String(d.bucket + "/" + srcKey),
+			CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", start, end)),
+		})
+		if err != nil {
+			abort()
+			return err
+		}
+		completedParts = append(completedParts, types.CompletedPart{
+			ETag:       out.CopyPartResult.ETag,
+			PartNumber: aws.Int32(partNumber),
+		})
+		partNumber++
+	}
+
+	if _, err := d.s3.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String(d.bucket),
+		Key:      aws.String(destKey),
+		UploadId: uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{
+			Parts: completedParts,
+		},
 	}); err != nil {
+		abort()
 		return err
 	}
+	return nil
+}
 
+// deleteObjects batch-deletes keys, chunking into DeleteObjects' maximum
+// batch size.
+func (d S3Driver) deleteObjects(ctx context.Context, keys []string) error {
+	for i := 0; i < len(keys); i += deleteObjectsBatchSize {
+		end := i + deleteObjectsBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		ids := make([]types.ObjectIdentifier, 0, end-i)
+		for _, key := range keys[i:end] {
+			ids = append(ids, types.ObjectIdentifier{Key: aws.String(key)})
+		}
+		if _, err := d.s3.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(d.bucket),
+			Delete: &types.Delete{Objects: ids},
+		}); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (d S3Driver) MakeDir(path string) error {
+func (d S3Driver) MakeDir(ctx context.Context, path string) error {
 	localPath, err := TranslatePath(d.prefix, d.homePath, path)
 	if err != nil {
 		return err
@@ -171,21 +413,51 @@ func (d S3Driver) MakeDir(path string) error {
 		localPath += "/"
 	}
 
-	_, err = d.s3.PutObject(&s3.PutObjectInput{
+	sse, kmsKeyID := d.serverSideEncryption()
+	_, err = d.s3.PutObject(ctx, &s3.PutObjectInput{
 		Bucket:               aws.String(d.bucket),
 		Key:                  aws.String(localPath),
-		ServerSideEncryption: aws.String("AES256"),
+		ServerSideEncryption: sse,
+		SSEKMSKeyId:          kmsKeyID,
 		Body:                 bytes.NewReader([]byte{}),
 	})
 	return err
 }
 
-func (d S3Driver) GetFile(path string) (io.ReadCloser, error) {
+// isBlockedIPAddress reports whether this driver's remoteIPAddress appears
+// in BLOCK_DOWNLOADS_IP_ADDRESSES.
+func (d S3Driver) isBlockedIPAddress() bool {
+	if d.remoteIPAddress == "" || len(BLOCK_DOWNLOADS_IP_ADDRESSES) == 0 {
+		return false
+	}
+	host, _, err := net.SplitHostPort(d.remoteIPAddress)
+	if err != nil {
+		host = d.remoteIPAddress
+	}
+	for _, blocked := range BLOCK_DOWNLOADS_IP_ADDRESSES {
+		if host == blocked {
+			return true
+		}
+	}
+	return false
+}
+
+func (d S3Driver) GetFile(ctx context.Context, path string) (io.ReadCloser, error) {
+	if d.isBlockedIPAddress() {
+		if d.lg != nil {
+			d.lg.ErrorD("s3-driver-blocked-download", map[string]interface{}{
+				"remote-ip-address": d.remoteIPAddress,
+				"path":              path,
+			})
+		}
+		return nil, fmt.Errorf("downloads are blocked for remote address %s", d.remoteIPAddress)
+	}
+
 	localPath, err := TranslatePath(d.prefix, d.homePath, path)
 	if err != nil {
 		return nil, err
 	}
-	obj, err := d.s3.GetObject(&s3.GetObjectInput{
+	obj, err := d.s3.GetObject(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(d.bucket),
 		Key:    aws.String(localPath),
 	})
@@ -195,24 +467,192 @@ func (d S3Driver) GetFile(path string) (io.ReadCloser, error) {
 	return obj.Body, nil
 }
 
-func (d S3Driver) PutFile(path string, r io.Reader) error {
+// PutFile uploads r to S3. Files that fit in a single part are written with
+// one PutObject call; larger files are streamed via a multipart upload using
+// bounded, fixed-size part buffers (see WithPartSize / WithUploadConcurrency)
+// so memory usage stays constant regardless of file size and the 5 GiB
+// single-PutObject limit no longer applies. If any part fails, the
+// in-progress multipart upload is aborted so no orphaned parts are left
+// accruing storage charges.
+func (d S3Driver) PutFile(ctx context.Context, path string, r io.Reader) error {
+	return d.putFile(ctx, path, r, "")
+}
+
+// putFile is PutFile plus a contentEncoding override, so wrappers like the
+// compression driver can tag the stored object without S3Driver needing to
+// know anything about compression itself.
+func (d S3Driver) putFile(ctx context.Context, path string, r io.Reader, contentEncoding string) error {
 	localPath, err := TranslatePath(d.prefix, d.homePath, path)
 	if err != nil {
 		return err
 	}
 
-	rawData, err := ioutil.ReadAll(r)
-	if err != nil {
+	partSize := d.partSize
+	if partSize == 0 {
+		partSize = defaultPartSize
+	}
+	sse, kmsKeyID := d.serverSideEncryption()
+
+	firstPart := make([]byte, partSize)
+	n, readErr := io.ReadFull(r, firstPart)
+	firstPart = firstPart[:n]
+	if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+		putInput := &s3.PutObjectInput{
+			Bucket:               aws.String(d.bucket),
+			Key:                  aws.String(localPath),
+			ServerSideEncryption: sse,
+			SSEKMSKeyId:          kmsKeyID,
+			Body:                 bytes.NewReader(firstPart),
+		}
+		if contentEncoding != "" {
+			putInput.ContentEncoding = aws.String(contentEncoding)
+		}
+		_, err := d.s3.PutObject(ctx, putInput)
 		return err
 	}
+	if readErr != nil {
+		return readErr
+	}
+
+	concurrency := d.uploadConcurrency
+	if concurrency == 0 {
+		concurrency = defaultUploadConcurrency
+	}
 
-	_, err = d.s3.PutObject(&s3.PutObjectInput{
+	createInput := &s3.CreateMultipartUploadInput{
 		Bucket:               aws.String(d.bucket),
 		Key:                  aws.String(localPath),
-		ServerSideEncryption: aws.String("AES256"),
-		Body:                 bytes.NewReader(rawData),
+		ServerSideEncryption: sse,
+		SSEKMSKeyId:          kmsKeyID,
+	}
+	if contentEncoding != "" {
+		createInput.ContentEncoding = aws.String(contentEncoding)
+	}
+	created, err := d.s3.CreateMultipartUpload(ctx, createInput)
+	if err != nil {
+		return err
+	}
+	uploadID := created.UploadId
+
+	abort := func() {
+		// Deliberately not ctx: if the caller's context is what's aborting
+		// this upload, using it here would mean the abort itself never
+		// fires, leaving orphaned parts accruing storage charges.
+		if _, err := d.s3.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(d.bucket),
+			Key:      aws.String(localPath),
+			UploadId: uploadID,
+		}); err != nil && d.lg != nil {
+			d.lg.ErrorD("s3-driver-abort-multipart-upload-failed", map[string]interface{}{
+				"key":   localPath,
+				"error": err.Error(),
+			})
+		}
+	}
+
+	var (
+		wg             sync.WaitGroup
+		sem            = make(chan struct{}, concurrency)
+		mu             sync.Mutex
+		completedParts []types.CompletedPart
+		firstErr       error
+	)
+
+	uploadPart := func(partNum int32, buf []byte) {
+		defer wg.Done()
+		defer func() { <-sem }()
+
+		out, err := d.s3.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     aws.String(d.bucket),
+			Key:        aws.String(localPath),
+			PartNumber: aws.Int32(partNum),
+			UploadId:   uploadID,
+			Body:       bytes.NewReader(buf),
+		})
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return
+		}
+		completedParts = append(completedParts, types.CompletedPart{
+			ETag:       out.ETag,
+			PartNumber: aws.Int32(partNum),
+		})
+	}
+
+	var partNumber int32 = 1
+	wg.Add(1)
+	sem <- struct{}{}
+	go uploadPart(partNumber, firstPart)
+	partNumber++
+
+	for {
+		buf := make([]byte, partSize)
+		n, readErr := io.ReadFull(r, buf)
+		if n > 0 {
+			buf = buf[:n]
+			partNum := partNumber
+			partNumber++
+
+			wg.Add(1)
+			sem <- struct{}{}
+
+			// A part already failed, so this upload is guaranteed to be
+			// aborted; stop uploading the rest of the stream. Checking after
+			// acquiring sem (rather than before) means this sees the result
+			// of every upload that's finished so far, not a stale snapshot.
+			mu.Lock()
+			failed := firstErr != nil
+			mu.Unlock()
+			if failed {
+				wg.Done()
+				<-sem
+				break
+			}
+
+			go uploadPart(partNum, buf)
+		}
+
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = readErr
+			}
+			mu.Unlock()
+			break
+		}
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		abort()
+		return firstErr
+	}
+
+	sort.Slice(completedParts, func(i, j int) bool {
+		return *completedParts[i].PartNumber < *completedParts[j].PartNumber
 	})
-	return err
+
+	if _, err := d.s3.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String(d.bucket),
+		Key:      aws.String(localPath),
+		UploadId: uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{
+			Parts: completedParts,
+		},
+	}); err != nil {
+		abort()
+		return err
+	}
+	return nil
 }
 
 // translatePath takes in a S3 root prefix, a home directory, and either an absolute or relative path to append, and returns a cleaned and validated path.
@@ -220,7 +660,7 @@ func (d S3Driver) PutFile(path string, r io.Reader) error {
 // It also preserves a single trailing slash if one is present, so it can be used on both directories and files.
 func TranslatePath(prefix, home, path string) (string, error) {
 	if path == "" {
-		return filepath.Clean("/" + prefix + "/" + home), nil
+		return strings.TrimLeft(filepath.Clean("/"+prefix+"/"+home), "/"), nil
 	}
 
 	var cleanPath string
@@ -240,19 +680,138 @@ func TranslatePath(prefix, home, path string) (string, error) {
 	return strings.TrimLeft(cleanPath, "/"), nil
 }
 
-// NewS3Driver creates a new S3Driver with the AWS credentials and S3 parameters.
+// S3DriverOption configures optional behavior on an S3Driver at construction time.
+type S3DriverOption func(*s3DriverConfig)
+
+// s3DriverConfig accumulates the effect of S3DriverOptions before the S3
+// client is constructed, since some options (like WithBaseEndpoint) have to
+// be applied to the client itself rather than the S3Driver struct.
+type s3DriverConfig struct {
+	driver       S3Driver
+	baseEndpoint string
+}
+
+// WithPartSize sets the size in bytes of each part uploaded by PutFile's multipart
+// upload. S3 requires parts (other than the last) to be at least 5 MiB. Defaults to
+// 8 MiB.
+func WithPartSize(size int64) S3DriverOption {
+	return func(c *s3DriverConfig) {
+		c.driver.partSize = size
+	}
+}
+
+// WithUploadConcurrency sets how many parts PutFile will upload to S3 in parallel.
+// Defaults to 4.
+func WithUploadConcurrency(n int) S3DriverOption {
+	return func(c *s3DriverConfig) {
+		c.driver.uploadConcurrency = n
+	}
+}
+
+// WithBaseEndpoint overrides the S3 endpoint the driver talks to, for use
+// with S3-compatible stores like MinIO or Ceph instead of AWS S3.
+func WithBaseEndpoint(endpoint string) S3DriverOption {
+	return func(c *s3DriverConfig) {
+		c.baseEndpoint = endpoint
+	}
+}
+
+// WithKMSKeyID configures the driver to encrypt objects it writes with the
+// given SSE-KMS key instead of the default SSE-S3 (AES256).
+func WithKMSKeyID(keyID string) S3DriverOption {
+	return func(c *s3DriverConfig) {
+		c.driver.kmsKeyID = aws.String(keyID)
+	}
+}
+
+// WithLogger sets the Logger the driver uses to record events like blocked
+// downloads.
+func WithLogger(lg Logger) S3DriverOption {
+	return func(c *s3DriverConfig) {
+		c.driver.lg = lg
+	}
+}
+
+// WithRemoteIPAddress records the connecting SFTP client's address
+// ("host:port"), so GetFile can enforce BLOCK_DOWNLOADS_IP_ADDRESSES. Callers
+// construct one S3Driver per connection, so this is set alongside the other
+// per-user options at construction time.
+func WithRemoteIPAddress(addr string) S3DriverOption {
+	return func(c *s3DriverConfig) {
+		c.driver.remoteIPAddress = addr
+	}
+}
+
+// NewS3Driver creates a new S3Driver with the given v2 aws.Config and S3 parameters.
+// bucket: name of S3 bucket
+// prefix: key within the S3 bucket, if applicable
+// homePath: default home directory for user (can be different from prefix)
+func NewS3Driver(cfg aws.Config, bucket, prefix, homePath string, opts ...S3DriverOption) *S3Driver {
+	conf := applyS3DriverOptions(bucket, prefix, homePath, opts)
+	conf.driver.s3 = s3.NewFromConfig(cfg, conf.clientOptFns()...)
+	return &conf.driver
+}
+
+// NewS3DriverWithAssumeRole creates a new S3Driver that authenticates by
+// assuming roleARN, scoped down to a session policy that only allows s3:*
+// actions under arn:aws:s3:::{bucket}/{prefix}/{homePath}/*. This lets
+// cross-account or multi-tenant callers share a single daemon without
+// granting it standing access to the whole bucket, and gives CloudTrail
+// attribution to the role actually assumed for this user rather than the
+// daemon's own credentials. The returned credentials are cached and
+// refreshed automatically before they expire via stscreds.AssumeRoleProvider.
+// cfg: an AWS config holding credentials allowed to assume roleARN
 // bucket: name of S3 bucket
 // prefix: key within the S3 bucket, if applicable
 // homePath: default home directory for user (can be different from prefix)
-func NewS3Driver(bucket, prefix, homePath, region, awsAccessKeyID, awsSecretKey, awsToken string) *S3Driver {
-	config := aws.NewConfig().
-		WithRegion(region).
-		WithCredentials(credentials.NewStaticCredentials(awsAccessKeyID, awsSecretKey, awsToken))
-	s3 := s3.New(session.New(), config)
-	return &S3Driver{
-		s3:       s3,
-		bucket:   bucket,
-		prefix:   prefix,
-		homePath: homePath,
+func NewS3DriverWithAssumeRole(cfg aws.Config, bucket, prefix, homePath, roleARN string, opts ...S3DriverOption) *S3Driver {
+	stsClient := sts.NewFromConfig(cfg)
+	assumeRoleCfg := cfg.Copy()
+	assumeRoleCfg.Credentials = aws.NewCredentialsCache(stscredsv2.NewAssumeRoleProvider(stsClient, roleARN, func(o *stscredsv2.AssumeRoleOptions) {
+		o.Policy = aws.String(userScopedSessionPolicy(bucket, prefix, homePath))
+	}))
+
+	conf := applyS3DriverOptions(bucket, prefix, homePath, opts)
+	conf.driver.s3 = s3.NewFromConfig(assumeRoleCfg, conf.clientOptFns()...)
+	return &conf.driver
+}
+
+func applyS3DriverOptions(bucket, prefix, homePath string, opts []S3DriverOption) *s3DriverConfig {
+	conf := &s3DriverConfig{
+		driver: S3Driver{
+			bucket:   bucket,
+			prefix:   prefix,
+			homePath: homePath,
+		},
 	}
+	for _, opt := range opts {
+		opt(conf)
+	}
+	return conf
+}
+
+func (c *s3DriverConfig) clientOptFns() []func(*s3.Options) {
+	if c.baseEndpoint == "" {
+		return nil
+	}
+	return []func(*s3.Options){
+		func(o *s3.Options) { o.BaseEndpoint = aws.String(c.baseEndpoint) },
+	}
+}
+
+// userScopedSessionPolicy returns an IAM session policy restricting s3:*
+// actions to the given user's slice of the bucket, for use with AssumeRole.
+func userScopedSessionPolicy(bucket, prefix, homePath string) string {
+	resource := fmt.Sprintf("arn:aws:s3:::%s/%s/*", bucket, path.Join(strings.Trim(prefix, "/"), strings.Trim(homePath, "/")))
+	policy, _ := json.Marshal(map[string]interface{}{
+		"Version": "2012-10-17",
+		"Statement": []map[string]interface{}{
+			{
+				"Effect":   "Allow",
+				"Action":   "s3:*",
+				"Resource": resource,
+			},
+		},
+	})
+	return string(policy)
 }
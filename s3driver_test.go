@@ -2,6 +2,8 @@ package sftp
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"testing"
 	"time"
 
@@ -86,7 +88,7 @@ func TestStat(t *testing.T) {
 		bucket:   "bucket",
 		homePath: "home",
 	}
-	info, err := driver.Stat("../../dir/file")
+	info, err := driver.Stat(context.Background(), "../../dir/file")
 
 	assert.NoError(t, err)
 	assert.Equal(t, info.Name(), "home/dir/file")
@@ -119,7 +121,7 @@ func TestListDir(t *testing.T) {
 		bucket:   "bucket",
 		homePath: "home",
 	}
-	files, err := driver.ListDir("../../dir/")
+	files, err := driver.ListDir(context.Background(), "../../dir/")
 
 	assert.NoError(t, err)
 	assert.Equal(t, len(files), 3)
@@ -146,7 +148,7 @@ func TestDeleteDir(t *testing.T) {
 		bucket:   "bucket",
 		homePath: "home",
 	}
-	err := driver.DeleteFile("../../dir/")
+	err := driver.DeleteFile(context.Background(), "../../dir/")
 
 	assert.NoError(t, err)
 }
@@ -166,7 +168,7 @@ func TestDeleteDirImplicitSlash(t *testing.T) {
 		bucket:   "bucket",
 		homePath: "home",
 	}
-	err := driver.DeleteDir("../../dir")
+	err := driver.DeleteDir(context.Background(), "../../dir")
 
 	assert.NoError(t, err)
 }
@@ -186,7 +188,7 @@ func TestDeleteFile(t *testing.T) {
 		bucket:   "bucket",
 		homePath: "home",
 	}
-	err := driver.DeleteFile("../../dir/file")
+	err := driver.DeleteFile(context.Background(), "../../dir/file")
 
 	assert.NoError(t, err)
 }
@@ -196,6 +198,25 @@ func TestRename(t *testing.T) {
 	defer mockCtrl.Finish()
 	mockS3API := NewMockS3API(mockCtrl)
 
+	mockS3API.EXPECT().ListObjectsV2(gomock.Any(), &s3.ListObjectsV2Input{
+		Bucket: aws.String("bucket"),
+		Prefix: aws.String("home/dir/file/"),
+	}).Return(&s3.ListObjectsV2Output{
+		KeyCount:    aws.Int32(0),
+		IsTruncated: aws.Bool(false),
+	}, nil)
+
+	mockS3API.EXPECT().ListObjectsV2(gomock.Any(), &s3.ListObjectsV2Input{
+		Bucket:  aws.String("bucket"),
+		Prefix:  aws.String("home/dir/file"),
+		MaxKeys: aws.Int32(1),
+	}).Return(&s3.ListObjectsV2Output{
+		KeyCount: aws.Int32(1),
+		Contents: []types.Object{
+			{Key: aws.String("home/dir/file"), Size: aws.Int64(10)},
+		},
+	}, nil)
+
 	mockS3API.EXPECT().CopyObject(gomock.Any(), &s3.CopyObjectInput{
 		Bucket:               aws.String("bucket"),
 		CopySource:           aws.String("bucket/home/dir/file"),
@@ -213,7 +234,7 @@ func TestRename(t *testing.T) {
 		bucket:   "bucket",
 		homePath: "home",
 	}
-	err := driver.Rename("dir/file", "dir/new_file")
+	err := driver.Rename(context.Background(), "dir/file", "dir/new_file")
 
 	assert.NoError(t, err)
 }
@@ -235,7 +256,7 @@ func TestRelativeMakeDir(t *testing.T) {
 		bucket:   "bucket",
 		homePath: "home",
 	}
-	assert.NoError(t, driver.MakeDir("new_dir"))
+	assert.NoError(t, driver.MakeDir(context.Background(), "new_dir"))
 }
 
 func TestAbsoluteMakeDir(t *testing.T) {
@@ -255,7 +276,7 @@ func TestAbsoluteMakeDir(t *testing.T) {
 		bucket:   "bucket",
 		homePath: "home",
 	}
-	err := driver.MakeDir("/new_dir")
+	err := driver.MakeDir(context.Background(), "/new_dir")
 
 	assert.NoError(t, err)
 }
@@ -277,14 +298,14 @@ func TestGetFile(t *testing.T) {
 		bucket:   "bucket",
 		homePath: "home",
 	}
-	_, err := driver.GetFile("../../dir/file")
+	_, err := driver.GetFile(context.Background(), "../../dir/file")
 
 	assert.NoError(t, err)
 }
 
 type testLogger struct{}
 
-func (l *testLogger) InfoD(title string, meta map[string]interface{}) {}
+func (l *testLogger) InfoD(title string, meta map[string]interface{})  {}
 func (l *testLogger) ErrorD(title string, meta map[string]interface{}) {}
 
 func TestGetFileFromBlockedIPAddress(t *testing.T) {
@@ -301,7 +322,7 @@ func TestGetFileFromBlockedIPAddress(t *testing.T) {
 		remoteIPAddress: "1.1.1.1:1234",
 		lg:              &testLogger{},
 	}
-	_, err := driver.GetFile("../../dir/file")
+	_, err := driver.GetFile(context.Background(), "../../dir/file")
 
 	assert.Error(t, err)
 
@@ -325,7 +346,7 @@ func TestPutFile(t *testing.T) {
 		bucket:   "bucket",
 		homePath: "home",
 	}
-	err := driver.PutFile("../../dir/file", bytes.NewReader([]byte{1, 2, 3}))
+	err := driver.PutFile(context.Background(), "../../dir/file", bytes.NewReader([]byte{1, 2, 3}))
 
 	assert.NoError(t, err)
 }
@@ -350,7 +371,164 @@ func TestPutFileWithKmsKeyID(t *testing.T) {
 		homePath: "home",
 		kmsKeyID: &kmsKeyID,
 	}
-	err := driver.PutFile("../../dir/file", bytes.NewReader([]byte{1, 2, 3}))
+	err := driver.PutFile(context.Background(), "../../dir/file", bytes.NewReader([]byte{1, 2, 3}))
 
 	assert.NoError(t, err)
 }
+
+func TestPutFileMultipart(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockS3API := NewMockS3API(mockCtrl)
+
+	mockS3API.EXPECT().CreateMultipartUpload(gomock.Any(), &s3.CreateMultipartUploadInput{
+		Bucket:               aws.String("bucket"),
+		Key:                  aws.String("home/dir/file"),
+		ServerSideEncryption: types.ServerSideEncryptionAes256,
+	}).Return(&s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil)
+
+	mockS3API.EXPECT().UploadPart(gomock.Any(), &s3.UploadPartInput{
+		Bucket:     aws.String("bucket"),
+		Key:        aws.String("home/dir/file"),
+		PartNumber: aws.Int32(1),
+		UploadId:   aws.String("upload-1"),
+		Body:       bytes.NewReader([]byte("aaaa")),
+	}).Return(&s3.UploadPartOutput{ETag: aws.String("etag-1")}, nil)
+
+	mockS3API.EXPECT().UploadPart(gomock.Any(), &s3.UploadPartInput{
+		Bucket:     aws.String("bucket"),
+		Key:        aws.String("home/dir/file"),
+		PartNumber: aws.Int32(2),
+		UploadId:   aws.String("upload-1"),
+		Body:       bytes.NewReader([]byte("bbbb")),
+	}).Return(&s3.UploadPartOutput{ETag: aws.String("etag-2")}, nil)
+
+	mockS3API.EXPECT().CompleteMultipartUpload(gomock.Any(), &s3.CompleteMultipartUploadInput{
+		Bucket:   aws.String("bucket"),
+		Key:      aws.String("home/dir/file"),
+		UploadId: aws.String("upload-1"),
+		MultipartUpload: &types.CompletedMultipartUpload{
+			Parts: []types.CompletedPart{
+				{ETag: aws.String("etag-1"), PartNumber: aws.Int32(1)},
+				{ETag: aws.String("etag-2"), PartNumber: aws.Int32(2)},
+			},
+		},
+	}).Return(nil, nil)
+
+	driver := &S3Driver{
+		s3:                mockS3API,
+		bucket:            "bucket",
+		homePath:          "home",
+		partSize:          4,
+		uploadConcurrency: 1,
+	}
+	err := driver.PutFile(context.Background(), "dir/file", bytes.NewReader([]byte("aaaabbbb")))
+
+	assert.NoError(t, err)
+}
+
+func TestPutFileMultipartStopsOnFirstPartFailure(t *testing.T) {
+	mockCtrl := gomock.NewController(t)
+	defer mockCtrl.Finish()
+	mockS3API := NewMockS3API(mockCtrl)
+
+	mockS3API.EXPECT().CreateMultipartUpload(gomock.Any(), &s3.CreateMultipartUploadInput{
+		Bucket:               aws.String("bucket"),
+		Key:                  aws.String("home/dir/file"),
+		ServerSideEncryption: types.ServerSideEncryptionAes256,
+	}).Return(&s3.CreateMultipartUploadOutput{UploadId: aws.String("upload-1")}, nil)
+
+	mockS3API.EXPECT().UploadPart(gomock.Any(), &s3.UploadPartInput{
+		Bucket:     aws.String("bucket"),
+		Key:        aws.String("home/dir/file"),
+		PartNumber: aws.Int32(1),
+		UploadId:   aws.String("upload-1"),
+		Body:       bytes.NewReader([]byte("aaaa")),
+	}).Return(&s3.UploadPartOutput{ETag: aws.String("etag-1")}, nil)
+
+	mockS3API.EXPECT().UploadPart(gomock.Any(), &s3.UploadPartInput{
+		Bucket:     aws.String("bucket"),
+		Key:        aws.String("home/dir/file"),
+		PartNumber: aws.Int32(2),
+		UploadId:   aws.String("upload-1"),
+		Body:       bytes.NewReader([]byte("bbbb")),
+	}).Return(nil, assert.AnError)
+
+	// Concurrency is 1, so parts upload strictly in order; no UploadPart for
+	// "cccc" or "dddd" is ever expected here, which asserts that putFile stops
+	// reading and uploading once a part has failed instead of wasting
+	// bandwidth on an upload that's already guaranteed to be aborted.
+	mockS3API.EXPECT().AbortMultipartUpload(gomock.Any(), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String("bucket"),
+		Key:      aws.String("home/dir/file"),
+		UploadId: aws.String("upload-1"),
+	}).Return(nil, nil)
+
+	driver := &S3Driver{
+		s3:                mockS3API,
+		bucket:            "bucket",
+		homePath:          "home",
+		partSize:          4,
+		uploadConcurrency: 1,
+	}
+	err := driver.PutFile(context.Background(), "dir/file", bytes.NewReader([]byte("aaaabbbbccccdddd")))
+
+	assert.Equal(t, assert.AnError, err)
+}
+
+func TestUserScopedSessionPolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		bucket       string
+		prefix       string
+		homePath     string
+		wantResource string
+	}{
+		{
+			name:     "no prefix",
+			bucket:   "my-bucket",
+			prefix:   "",
+			homePath: "alice",
+			// With no prefix, the resource must match the unprefixed keys
+			// TranslatePath actually produces (e.g. "alice/file.txt"), so
+			// the empty prefix segment has to collapse rather than leave a
+			// literal double slash.
+			wantResource: "arn:aws:s3:::my-bucket/alice/*",
+		},
+		{
+			name:         "with prefix",
+			bucket:       "my-bucket",
+			prefix:       "tenants",
+			homePath:     "alice",
+			wantResource: "arn:aws:s3:::my-bucket/tenants/alice/*",
+		},
+		{
+			name:         "leading and trailing slashes are trimmed",
+			bucket:       "my-bucket",
+			prefix:       "/tenants/",
+			homePath:     "/bob/",
+			wantResource: "arn:aws:s3:::my-bucket/tenants/bob/*",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policyJSON := userScopedSessionPolicy(tt.bucket, tt.prefix, tt.homePath)
+
+			var policy struct {
+				Version   string
+				Statement []struct {
+					Effect   string
+					Action   string
+					Resource string
+				}
+			}
+			assert.NoError(t, json.Unmarshal([]byte(policyJSON), &policy))
+
+			assert.Len(t, policy.Statement, 1)
+			assert.Equal(t, "Allow", policy.Statement[0].Effect)
+			assert.Equal(t, "s3:*", policy.Statement[0].Action)
+			assert.Equal(t, tt.wantResource, policy.Statement[0].Resource)
+		})
+	}
+}